@@ -0,0 +1,103 @@
+package utils
+
+import (
+	"crypto/sha512"
+	"errors"
+
+	"golang.org/x/crypto/blowfish"
+)
+
+// bcryptPBKDFBlockSize is the size, in bytes, of a single bcrypt_pbkdf hash
+// output block.
+const bcryptPBKDFBlockSize = 32
+
+// bcryptPBKDF derives a key of length keyLen from password and salt using
+// the bcrypt_pbkdf KDF that OpenSSH uses to protect its "openssh-key-v1"
+// private key format. It is implemented locally on top of
+// golang.org/x/crypto/blowfish (already a transitive dependency of this
+// module via utils.PrivateKeyToEncryptedPKCS8PEM's use of
+// golang.org/x/crypto/pbkdf2) so that this format does not pull in a new
+// external module.
+func bcryptPBKDF(password, salt []byte, rounds, keyLen int) ([]byte, error) {
+	if rounds < 1 {
+		return nil, errors.New("bcryptPBKDF: invalid rounds")
+	}
+	if len(password) == 0 {
+		return nil, errors.New("bcryptPBKDF: empty password")
+	}
+	if len(salt) == 0 {
+		return nil, errors.New("bcryptPBKDF: empty salt")
+	}
+	if keyLen <= 0 {
+		return nil, errors.New("bcryptPBKDF: invalid key length")
+	}
+
+	numBlocks := (keyLen + bcryptPBKDFBlockSize - 1) / bcryptPBKDFBlockSize
+	key := make([]byte, numBlocks*bcryptPBKDFBlockSize)
+
+	shaPass := sha512.Sum512(password)
+
+	var cnt [4]byte
+	tmp := make([]byte, bcryptPBKDFBlockSize)
+	out := make([]byte, bcryptPBKDFBlockSize)
+
+	for block := 1; block <= numBlocks; block++ {
+		cnt[0] = byte(block >> 24)
+		cnt[1] = byte(block >> 16)
+		cnt[2] = byte(block >> 8)
+		cnt[3] = byte(block)
+
+		h := sha512.New()
+		h.Write(salt)
+		h.Write(cnt[:])
+		shaSalt := h.Sum(nil)
+
+		bcryptHash(tmp, shaPass[:], shaSalt)
+		copy(out, tmp)
+
+		for i := 1; i < rounds; i++ {
+			h := sha512.Sum512(tmp)
+			bcryptHash(tmp, shaPass[:], h[:])
+			for j := range out {
+				out[j] ^= tmp[j]
+			}
+		}
+
+		for i, v := range out {
+			idx := i*numBlocks + (block - 1)
+			key[idx] = v
+		}
+	}
+
+	return key[:keyLen], nil
+}
+
+// bcryptHash computes the core "Blowfish in a loop" hash at the heart of
+// bcrypt_pbkdf: it key-schedules a Blowfish cipher from (sha2pass, sha2salt)
+// over 64 expansion rounds, then encrypts a fixed 32-byte magic string 64
+// times in ECB mode, swapping each 4-byte group's endianness on the way out.
+func bcryptHash(out, sha2pass, sha2salt []byte) {
+	ciphertext := []byte("OxychromaticBlowfishSwatDynamite")
+
+	c, err := blowfish.NewSaltedCipher(sha2pass, sha2salt)
+	if err != nil {
+		panic(err)
+	}
+	for i := 0; i < 64; i++ {
+		blowfish.ExpandKey(sha2salt, c)
+		blowfish.ExpandKey(sha2pass, c)
+	}
+
+	for i := 0; i < 64; i++ {
+		for j := 0; j < len(ciphertext); j += 8 {
+			c.Encrypt(ciphertext[j:j+8], ciphertext[j:j+8])
+		}
+	}
+
+	for i := 0; i < len(ciphertext); i += 4 {
+		out[i+0] = ciphertext[i+3]
+		out[i+1] = ciphertext[i+2]
+		out[i+2] = ciphertext[i+1]
+		out[i+3] = ciphertext[i+0]
+	}
+}
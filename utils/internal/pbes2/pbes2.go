@@ -0,0 +1,215 @@
+// Package pbes2 implements the PBES2/PBKDF2/AES-256-CBC
+// EncryptedPrivateKeyInfo construction (RFC 5958, RFC 8018), along with the
+// SM2 ecPrivateKey/PKCS#8 ASN.1 shapes built on top of it, shared by utils
+// and utils/pkcs12 so the two packages don't each maintain an independent
+// copy of this crypto-sensitive ASN.1 code.
+package pbes2
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/asn1"
+	"errors"
+	"fmt"
+
+	"github.com/hw09234/gm-crypto/sm2"
+	"golang.org/x/crypto/pbkdf2"
+)
+
+const (
+	SaltLen      = 16
+	IVLen        = aes.BlockSize
+	AES256KeyLen = 32
+)
+
+var (
+	OIDPBES2          = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 5, 13}
+	OIDPBKDF2         = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 5, 12}
+	OIDHMACWithSHA256 = asn1.ObjectIdentifier{1, 2, 840, 113549, 2, 9}
+	OIDAES256CBC      = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 1, 42}
+
+	OIDNamedCurveSm2  = asn1.ObjectIdentifier{1, 2, 156, 10197, 1, 301}
+	OIDPublicKeyECDSA = asn1.ObjectIdentifier{1, 2, 840, 10045, 2, 1}
+)
+
+// AlgorithmIdentifier mirrors the X.509 AlgorithmIdentifier SEQUENCE. It is
+// declared locally, rather than reusing crypto/x509/pkix, to keep this
+// package independent of the standard library's X.509 parser.
+type AlgorithmIdentifier struct {
+	Algorithm  asn1.ObjectIdentifier
+	Parameters asn1.RawValue `asn1:"optional"`
+}
+
+type PBKDF2Params struct {
+	Salt           []byte
+	IterationCount int
+	PRF            AlgorithmIdentifier `asn1:"optional"`
+}
+
+type PBES2Params struct {
+	KeyDerivationFunc AlgorithmIdentifier
+	EncryptionScheme  AlgorithmIdentifier
+}
+
+type EncryptedPrivateKeyInfo struct {
+	Algo          AlgorithmIdentifier
+	EncryptedData []byte
+}
+
+// ECPrivateKey mirrors the SEC1 ECPrivateKey ASN.1 shape used to carry an
+// SM2 private key inside a PKCS#8 PrivateKeyInfo.
+type ECPrivateKey struct {
+	Version       int
+	PrivateKey    []byte
+	NamedCurveOID asn1.ObjectIdentifier `asn1:"optional,explicit,tag:0"`
+	PublicKey     asn1.BitString        `asn1:"optional,explicit,tag:1"`
+}
+
+// PKCS8Info mirrors the PKCS#8 PrivateKeyInfo SEQUENCE.
+type PKCS8Info struct {
+	Version             int
+	PrivateKeyAlgorithm []asn1.ObjectIdentifier
+	PrivateKey          []byte
+}
+
+// MarshalSM2PKCS8 marshals an SM2 private key to its unencrypted PKCS#8 DER
+// encoding, the plaintext that is then PBES2-encrypted into an
+// EncryptedPrivateKeyInfo.
+func MarshalSM2PKCS8(priv *sm2.PrivateKey) ([]byte, error) {
+	privateKeyBytes := priv.D.Bytes()
+	paddedPrivateKey := make([]byte, (priv.Curve.Params().N.BitLen()+7)/8)
+	copy(paddedPrivateKey[len(paddedPrivateKey)-len(privateKeyBytes):], privateKeyBytes)
+
+	asn1Bytes, err := asn1.Marshal(ECPrivateKey{
+		Version:       1,
+		PrivateKey:    paddedPrivateKey,
+		NamedCurveOID: OIDNamedCurveSm2,
+		PublicKey:     asn1.BitString{Bytes: elliptic.Marshal(priv.Curve, priv.X, priv.Y)},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error marshaling SM2 key to asn1 [%s]", err)
+	}
+
+	pkcs8Bytes, err := asn1.Marshal(PKCS8Info{
+		Version:             0,
+		PrivateKeyAlgorithm: []asn1.ObjectIdentifier{OIDPublicKeyECDSA, OIDNamedCurveSm2},
+		PrivateKey:          asn1Bytes,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error marshaling EC key to asn1 [%s]", err)
+	}
+	return pkcs8Bytes, nil
+}
+
+// Encrypt encrypts plaintext with a freshly generated salt and IV under
+// PBES2 (PBKDF2 + HMAC-SHA256 PRF, AES-256-CBC), returning the ciphertext
+// and the AlgorithmIdentifier describing how to reverse it.
+func Encrypt(plaintext, pwd []byte, iterations int) ([]byte, AlgorithmIdentifier, error) {
+	salt := make([]byte, SaltLen)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, AlgorithmIdentifier{}, err
+	}
+	iv := make([]byte, IVLen)
+	if _, err := rand.Read(iv); err != nil {
+		return nil, AlgorithmIdentifier{}, err
+	}
+
+	key := pbkdf2.Key(pwd, salt, iterations, AES256KeyLen, sha256.New)
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, AlgorithmIdentifier{}, err
+	}
+	padded := pkcs7Pad(plaintext, block.BlockSize())
+	encrypted := make([]byte, len(padded))
+	cipher.NewCBCEncrypter(block, iv).CryptBlocks(encrypted, padded)
+
+	rawPBKDF2Params, err := asn1.Marshal(PBKDF2Params{
+		Salt:           salt,
+		IterationCount: iterations,
+		PRF:            AlgorithmIdentifier{Algorithm: OIDHMACWithSHA256},
+	})
+	if err != nil {
+		return nil, AlgorithmIdentifier{}, err
+	}
+	rawIV, err := asn1.Marshal(iv)
+	if err != nil {
+		return nil, AlgorithmIdentifier{}, err
+	}
+	rawPBES2Params, err := asn1.Marshal(PBES2Params{
+		KeyDerivationFunc: AlgorithmIdentifier{Algorithm: OIDPBKDF2, Parameters: asn1.RawValue{FullBytes: rawPBKDF2Params}},
+		EncryptionScheme:  AlgorithmIdentifier{Algorithm: OIDAES256CBC, Parameters: asn1.RawValue{FullBytes: rawIV}},
+	})
+	if err != nil {
+		return nil, AlgorithmIdentifier{}, err
+	}
+
+	return encrypted, AlgorithmIdentifier{Algorithm: OIDPBES2, Parameters: asn1.RawValue{FullBytes: rawPBES2Params}}, nil
+}
+
+// Decrypt reverses Encrypt given the AlgorithmIdentifier stored alongside
+// the ciphertext.
+func Decrypt(algo AlgorithmIdentifier, ciphertext, pwd []byte) ([]byte, error) {
+	if !algo.Algorithm.Equal(OIDPBES2) {
+		return nil, fmt.Errorf("unsupported content encryption algorithm %v", algo.Algorithm)
+	}
+	var params PBES2Params
+	if _, err := asn1.Unmarshal(algo.Parameters.FullBytes, &params); err != nil {
+		return nil, fmt.Errorf("failed parsing PBES2 parameters [%s]", err)
+	}
+	if !params.KeyDerivationFunc.Algorithm.Equal(OIDPBKDF2) {
+		return nil, fmt.Errorf("unsupported key derivation function %v", params.KeyDerivationFunc.Algorithm)
+	}
+	if !params.EncryptionScheme.Algorithm.Equal(OIDAES256CBC) {
+		return nil, fmt.Errorf("unsupported encryption scheme %v", params.EncryptionScheme.Algorithm)
+	}
+
+	var kdfParams PBKDF2Params
+	if _, err := asn1.Unmarshal(params.KeyDerivationFunc.Parameters.FullBytes, &kdfParams); err != nil {
+		return nil, fmt.Errorf("failed parsing PBKDF2 parameters [%s]", err)
+	}
+	if kdfParams.PRF.Algorithm != nil && !kdfParams.PRF.Algorithm.Equal(OIDHMACWithSHA256) {
+		return nil, fmt.Errorf("unsupported PBKDF2 PRF %v", kdfParams.PRF.Algorithm)
+	}
+	var iv []byte
+	if _, err := asn1.Unmarshal(params.EncryptionScheme.Parameters.FullBytes, &iv); err != nil {
+		return nil, fmt.Errorf("failed parsing AES-256-CBC IV [%s]", err)
+	}
+
+	key := pbkdf2.Key(pwd, kdfParams.Salt, kdfParams.IterationCount, AES256KeyLen, sha256.New)
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(ciphertext) == 0 || len(ciphertext)%block.BlockSize() != 0 {
+		return nil, errors.New("invalid encrypted content")
+	}
+	decrypted := make([]byte, len(ciphertext))
+	cipher.NewCBCDecrypter(block, iv).CryptBlocks(decrypted, ciphertext)
+	return pkcs7Unpad(decrypted, block.BlockSize())
+}
+
+func pkcs7Pad(data []byte, blockSize int) []byte {
+	padLen := blockSize - len(data)%blockSize
+	padding := bytes.Repeat([]byte{byte(padLen)}, padLen)
+	return append(data, padding...)
+}
+
+func pkcs7Unpad(data []byte, blockSize int) ([]byte, error) {
+	if len(data) == 0 || len(data)%blockSize != 0 {
+		return nil, errors.New("invalid PKCS#7 padded data")
+	}
+	padLen := int(data[len(data)-1])
+	if padLen == 0 || padLen > blockSize || padLen > len(data) {
+		return nil, errors.New("invalid PKCS#7 padding")
+	}
+	for _, b := range data[len(data)-padLen:] {
+		if int(b) != padLen {
+			return nil, errors.New("invalid PKCS#7 padding")
+		}
+	}
+	return data[:len(data)-padLen], nil
+}
@@ -0,0 +1,172 @@
+// Package jwk marshals and parses SM2 keys in JSON Web Key (JWK, RFC 7517)
+// form, using the "SM2" curve identifier, so that SM2 keys can be published
+// at standard /.well-known/jwks.json endpoints and consumed by OIDC/JOSE
+// stacks that already understand JWK.
+package jwk
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/hw09234/gm-crypto/sm2"
+	"github.com/hw09234/gm-crypto/sm3"
+	"github.com/hw09234/gm-crypto/x509"
+)
+
+// crvSM2 is the "crv" value used for SM2 keys. It is not a registered JOSE
+// curve name; interoperability is limited to parties that understand the GM
+// key suite, the same tradeoff accepted by every SM2 JOSE profile in use
+// today.
+const crvSM2 = "SM2"
+
+const ktyEC = "EC"
+
+// coordByteLen is the fixed-width encoding length for SM2 field elements
+// (sm2p256v1 has a 256-bit field).
+const coordByteLen = 32
+
+// JWK is a JSON Web Key representing an SM2 public or private key.
+type JWK struct {
+	Kty string `json:"kty"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+	D   string `json:"d,omitempty"`
+	Kid string `json:"kid,omitempty"`
+}
+
+// JWKSet is a JSON Web Key Set (RFC 7517 section 5), the form expected at
+// /.well-known/jwks.json.
+type JWKSet struct {
+	Keys []*JWK `json:"keys"`
+}
+
+// PublicKeyToJWK marshals an SM2 public key to JWK form, with kid set to the
+// base64url-encoded SM3 hash of the key's DER-encoded SubjectPublicKeyInfo.
+func PublicKeyToJWK(pub *sm2.PublicKey) (*JWK, error) {
+	if pub == nil {
+		return nil, errors.New("invalid public key. It must be different from nil")
+	}
+
+	kid, err := keyID(pub)
+	if err != nil {
+		return nil, err
+	}
+
+	return &JWK{
+		Kty: ktyEC,
+		Crv: crvSM2,
+		X:   encodeCoord(pub.X),
+		Y:   encodeCoord(pub.Y),
+		Kid: kid,
+	}, nil
+}
+
+// PrivateKeyToJWK marshals an SM2 private key to JWK form, including the
+// private scalar "d" alongside the public coordinates.
+func PrivateKeyToJWK(priv *sm2.PrivateKey) (*JWK, error) {
+	if priv == nil {
+		return nil, errors.New("invalid private key. It must be different from nil")
+	}
+
+	j, err := PublicKeyToJWK(&priv.PublicKey)
+	if err != nil {
+		return nil, err
+	}
+	j.D = encodeCoord(priv.D)
+	return j, nil
+}
+
+// JWKToPublicKey parses a JWK produced by PublicKeyToJWK or PrivateKeyToJWK
+// back into an SM2 public key.
+func JWKToPublicKey(j *JWK) (*sm2.PublicKey, error) {
+	if j == nil {
+		return nil, errors.New("invalid JWK. It must be different from nil")
+	}
+	if j.Kty != ktyEC {
+		return nil, fmt.Errorf("unsupported kty %q", j.Kty)
+	}
+	if j.Crv != crvSM2 {
+		return nil, fmt.Errorf("unsupported crv %q", j.Crv)
+	}
+
+	x, err := decodeCoord(j.X)
+	if err != nil {
+		return nil, fmt.Errorf("invalid x coordinate [%s]", err)
+	}
+	y, err := decodeCoord(j.Y)
+	if err != nil {
+		return nil, fmt.Errorf("invalid y coordinate [%s]", err)
+	}
+
+	curve := sm2.P256()
+	return &sm2.PublicKey{
+		Curve: curve,
+		X:     x,
+		Y:     y,
+	}, nil
+}
+
+// JWKToPrivateKey parses a JWK produced by PrivateKeyToJWK back into an SM2
+// private key. It fails if the JWK has no "d" member.
+func JWKToPrivateKey(j *JWK) (*sm2.PrivateKey, error) {
+	if j == nil {
+		return nil, errors.New("invalid JWK. It must be different from nil")
+	}
+	if j.D == "" {
+		return nil, errors.New("JWK has no private key component \"d\"")
+	}
+
+	pub, err := JWKToPublicKey(j)
+	if err != nil {
+		return nil, err
+	}
+	d, err := decodeCoord(j.D)
+	if err != nil {
+		return nil, fmt.Errorf("invalid d value [%s]", err)
+	}
+
+	return &sm2.PrivateKey{
+		PublicKey: *pub,
+		D:         d,
+	}, nil
+}
+
+// ParseJWKSet parses a JSON Web Key Set document, as typically served at
+// /.well-known/jwks.json.
+func ParseJWKSet(data []byte) (*JWKSet, error) {
+	var set JWKSet
+	if err := json.Unmarshal(data, &set); err != nil {
+		return nil, fmt.Errorf("failed parsing JWK set [%s]", err)
+	}
+	return &set, nil
+}
+
+// keyID computes the kid used for a JWK: the base64url (no padding)
+// encoding of the SM3 hash of the key's DER-encoded SubjectPublicKeyInfo.
+func keyID(pub *sm2.PublicKey) (string, error) {
+	der, err := x509.MarshalPKIXPublicKey(pub)
+	if err != nil {
+		return "", fmt.Errorf("failed marshaling SPKI [%s]", err)
+	}
+	h := sm3.New()
+	h.Write(der)
+	return base64.RawURLEncoding.EncodeToString(h.Sum(nil)), nil
+}
+
+func encodeCoord(v *big.Int) string {
+	b := make([]byte, coordByteLen)
+	v.FillBytes(b)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func decodeCoord(s string) (*big.Int, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+	return new(big.Int).SetBytes(b), nil
+}
@@ -0,0 +1,52 @@
+package jwk
+
+import (
+	"crypto/rand"
+	"testing"
+
+	"github.com/hw09234/gm-crypto/sm2"
+)
+
+func TestPrivateKeyToJWKRoundTrip(t *testing.T) {
+	priv, err := sm2.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("sm2.GenerateKey failed [%s]", err)
+	}
+
+	j, err := PrivateKeyToJWK(priv)
+	if err != nil {
+		t.Fatalf("PrivateKeyToJWK failed [%s]", err)
+	}
+
+	got, err := JWKToPrivateKey(j)
+	if err != nil {
+		t.Fatalf("JWKToPrivateKey failed [%s]", err)
+	}
+	if got.D.Cmp(priv.D) != 0 {
+		t.Fatal("recovered private key does not match the original")
+	}
+	if got.X.Cmp(priv.X) != 0 || got.Y.Cmp(priv.Y) != 0 {
+		t.Fatal("recovered public key does not match the original")
+	}
+}
+
+func TestJWKToPublicKeyUnsupportedCrv(t *testing.T) {
+	j := &JWK{Kty: ktyEC, Crv: "P-256", X: "AA", Y: "AA"}
+	if _, err := JWKToPublicKey(j); err == nil {
+		t.Fatal("expected an error for an unsupported crv, got nil")
+	}
+}
+
+func TestJWKToPrivateKeyMissingD(t *testing.T) {
+	priv, err := sm2.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("sm2.GenerateKey failed [%s]", err)
+	}
+	j, err := PublicKeyToJWK(&priv.PublicKey)
+	if err != nil {
+		t.Fatalf("PublicKeyToJWK failed [%s]", err)
+	}
+	if _, err := JWKToPrivateKey(j); err == nil {
+		t.Fatal("expected an error for a JWK with no \"d\" member, got nil")
+	}
+}
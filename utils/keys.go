@@ -124,6 +124,22 @@ func PEMtoPrivateKey(raw []byte, pwd []byte) (*sm2.PrivateKey, error) {
 		return nil, fmt.Errorf("failed decoding PEM. Block must be different from nil. [% x]", raw)
 	}
 
+	if block.Type == "ENCRYPTED PRIVATE KEY" {
+		decrypted, err := parseEncryptedPKCS8PEMBlock(block, pwd)
+		if err != nil {
+			return nil, err
+		}
+		key, err := x509.ParsePKCS8PrivateKey(decrypted)
+		if err != nil {
+			return nil, err
+		}
+		sm2Key, ok := key.(*sm2.PrivateKey)
+		if ok {
+			return sm2Key, nil
+		}
+		return nil, errors.New("key type error")
+	}
+
 	if x509.IsEncryptedPEMBlock(block) {
 		if len(pwd) == 0 {
 			return nil, errors.New("encrypted Key. Need a password")
@@ -0,0 +1,231 @@
+package utils
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	stdx509 "crypto/x509"
+	"encoding/asn1"
+	"encoding/pem"
+	"errors"
+	"fmt"
+
+	"github.com/hw09234/gm-crypto/sm2"
+	"github.com/hw09234/gm-crypto/x509"
+)
+
+// oidEd25519 is the algorithm identifier for Ed25519 keys, as defined in
+// RFC 8410.
+var oidEd25519 = asn1.ObjectIdentifier{1, 3, 101, 112}
+
+// pkcs8SimpleAlgo is a PKCS#8 PrivateKeyInfo whose algorithm identifier
+// carries no parameters, as used by Ed25519 (RFC 8410).
+type pkcs8SimpleAlgo struct {
+	Version int
+	Algo    struct {
+		Algorithm asn1.ObjectIdentifier
+	}
+	PrivateKey []byte
+}
+
+// PrivateKeyToPEMAny converts a private key to PEM format regardless of its
+// concrete algorithm. SM2 keys are encoded exactly as PrivateKeyToPEM would;
+// ECDSA keys are encoded as "EC PRIVATE KEY" (SEC1); RSA keys are encoded as
+// "RSA PRIVATE KEY" (PKCS#1); Ed25519 keys are wrapped in PKCS#8 as
+// "PRIVATE KEY". If pwd is non-empty the result is an encrypted PKCS#8
+// "ENCRYPTED PRIVATE KEY" PEM block (see PrivateKeyToEncryptedPKCS8PEM).
+func PrivateKeyToPEMAny(key crypto.PrivateKey, pwd []byte) ([]byte, error) {
+	if key == nil {
+		return nil, errors.New("invalid key. It must be different from nil")
+	}
+
+	if len(pwd) != 0 {
+		der, err := marshalPKCS8DERAny(key)
+		if err != nil {
+			return nil, err
+		}
+		return encryptPKCS8DER(der, pwd, DefaultPBKDF2Iterations)
+	}
+
+	switch k := key.(type) {
+	case *sm2.PrivateKey:
+		return PrivateKeyToPEM(k, nil)
+	case *ecdsa.PrivateKey:
+		der, err := stdx509.MarshalECPrivateKey(k)
+		if err != nil {
+			return nil, fmt.Errorf("error marshaling ECDSA key [%s]", err)
+		}
+		return pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: der}), nil
+	case *rsa.PrivateKey:
+		return pem.EncodeToMemory(&pem.Block{
+			Type:  "RSA PRIVATE KEY",
+			Bytes: stdx509.MarshalPKCS1PrivateKey(k),
+		}), nil
+	case ed25519.PrivateKey:
+		der, err := marshalEd25519PKCS8(k)
+		if err != nil {
+			return nil, err
+		}
+		return pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), nil
+	default:
+		return nil, fmt.Errorf("unsupported private key type %T", key)
+	}
+}
+
+// PEMtoPrivateKeyAny parses a PEM-encoded private key of any of the types
+// produced by PrivateKeyToPEMAny (SM2, ECDSA, RSA, Ed25519), returning it as
+// a crypto.Signer.
+func PEMtoPrivateKeyAny(raw []byte, pwd []byte) (crypto.Signer, error) {
+	if len(raw) == 0 {
+		return nil, errors.New("invalid PEM. It must be different from nil")
+	}
+	block, _ := pem.Decode(raw)
+	if block == nil {
+		return nil, fmt.Errorf("failed decoding PEM. Block must be different from nil. [% x]", raw)
+	}
+
+	switch block.Type {
+	case "EC PRIVATE KEY":
+		if x509.IsEncryptedPEMBlock(block) {
+			if len(pwd) == 0 {
+				return nil, errors.New("encrypted key. Need a password")
+			}
+			decrypted, err := x509.DecryptPEMBlock(block, pwd)
+			if err != nil {
+				return nil, fmt.Errorf("failed PEM decryption [%s]", err)
+			}
+			return stdx509.ParseECPrivateKey(decrypted)
+		}
+		return stdx509.ParseECPrivateKey(block.Bytes)
+	case "RSA PRIVATE KEY":
+		if x509.IsEncryptedPEMBlock(block) {
+			if len(pwd) == 0 {
+				return nil, errors.New("encrypted key. Need a password")
+			}
+			decrypted, err := x509.DecryptPEMBlock(block, pwd)
+			if err != nil {
+				return nil, fmt.Errorf("failed PEM decryption [%s]", err)
+			}
+			return stdx509.ParsePKCS1PrivateKey(decrypted)
+		}
+		return stdx509.ParsePKCS1PrivateKey(block.Bytes)
+	case "ENCRYPTED PRIVATE KEY":
+		decrypted, err := parseEncryptedPKCS8PEMBlock(block, pwd)
+		if err != nil {
+			return nil, err
+		}
+		return signerFromPKCS8DER(decrypted)
+	case "PRIVATE KEY":
+		return signerFromPKCS8DER(block.Bytes)
+	default:
+		return nil, fmt.Errorf("unsupported PEM block type %q", block.Type)
+	}
+}
+
+// PublicKeyToPEMAny converts a public key of any supported algorithm (SM2,
+// ECDSA, RSA, Ed25519) to a PKIX "PUBLIC KEY" PEM block.
+func PublicKeyToPEMAny(key crypto.PublicKey) ([]byte, error) {
+	if key == nil {
+		return nil, errors.New("invalid public key. It must be different from nil")
+	}
+
+	var der []byte
+	var err error
+	switch k := key.(type) {
+	case *sm2.PublicKey:
+		return PublicKeyToPEM(k, nil)
+	case *ecdsa.PublicKey, *rsa.PublicKey, ed25519.PublicKey:
+		der, err = stdx509.MarshalPKIXPublicKey(k)
+	default:
+		return nil, fmt.Errorf("unsupported public key type %T", key)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}), nil
+}
+
+// PEMtoPublicKeyAny parses a PKIX "PUBLIC KEY" PEM block produced by
+// PublicKeyToPEMAny into a public key of the concrete algorithm it encodes.
+func PEMtoPublicKeyAny(raw []byte) (crypto.PublicKey, error) {
+	if len(raw) == 0 {
+		return nil, errors.New("invalid PEM. It must be different from nil")
+	}
+	block, _ := pem.Decode(raw)
+	if block == nil {
+		return nil, fmt.Errorf("failed decoding. Block must be different from nil. [% x]", raw)
+	}
+
+	if key, err := x509.ParsePKIXPublicKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	return stdx509.ParsePKIXPublicKey(block.Bytes)
+}
+
+// marshalPKCS8DERAny returns the unencrypted PKCS#8 DER encoding of key,
+// used as the payload before PBES2 encryption in PrivateKeyToPEMAny.
+func marshalPKCS8DERAny(key crypto.PrivateKey) ([]byte, error) {
+	switch k := key.(type) {
+	case *sm2.PrivateKey:
+		return marshalSM2PKCS8(k)
+	case *ecdsa.PrivateKey:
+		return stdx509.MarshalPKCS8PrivateKey(k)
+	case *rsa.PrivateKey:
+		return stdx509.MarshalPKCS8PrivateKey(k)
+	case ed25519.PrivateKey:
+		return marshalEd25519PKCS8(k)
+	default:
+		return nil, fmt.Errorf("unsupported private key type %T", key)
+	}
+}
+
+// marshalEd25519PKCS8 wraps an Ed25519 seed in a PKCS#8 PrivateKeyInfo per
+// RFC 8410: the CurvePrivateKey field is itself an OCTET STRING wrapping the
+// raw 32-byte seed.
+func marshalEd25519PKCS8(key ed25519.PrivateKey) ([]byte, error) {
+	curvePrivateKey, err := asn1.Marshal(key.Seed())
+	if err != nil {
+		return nil, err
+	}
+	info := pkcs8SimpleAlgo{
+		Version:    0,
+		PrivateKey: curvePrivateKey,
+	}
+	info.Algo.Algorithm = oidEd25519
+	return asn1.Marshal(info)
+}
+
+// signerFromPKCS8DER parses a PKCS#8 PrivateKeyInfo and returns it as a
+// crypto.Signer, supporting SM2 (via the gm-crypto x509 fork) and Ed25519
+// (handled locally, since Ed25519 carries no curve parameters for the gm
+// x509 parser to recognize) in addition to whatever stdlib's PKCS#8 parser
+// understands.
+func signerFromPKCS8DER(der []byte) (crypto.Signer, error) {
+	var peek pkcs8SimpleAlgo
+	if _, err := asn1.Unmarshal(der, &peek); err == nil && peek.Algo.Algorithm.Equal(oidEd25519) {
+		var seed []byte
+		if _, err := asn1.Unmarshal(peek.PrivateKey, &seed); err != nil {
+			return nil, fmt.Errorf("failed parsing Ed25519 seed [%s]", err)
+		}
+		return ed25519.NewKeyFromSeed(seed), nil
+	}
+
+	if key, err := x509.ParsePKCS8PrivateKey(der); err == nil {
+		signer, ok := key.(crypto.Signer)
+		if !ok {
+			return nil, fmt.Errorf("key type %T does not implement crypto.Signer", key)
+		}
+		return signer, nil
+	}
+
+	key, err := stdx509.ParsePKCS8PrivateKey(der)
+	if err != nil {
+		return nil, err
+	}
+	signer, ok := key.(crypto.Signer)
+	if !ok {
+		return nil, fmt.Errorf("key type %T does not implement crypto.Signer", key)
+	}
+	return signer, nil
+}
@@ -0,0 +1,113 @@
+package utils
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"testing"
+
+	"github.com/hw09234/gm-crypto/sm2"
+)
+
+func TestPrivateKeyToPEMAnyRoundTrip(t *testing.T) {
+	ecdsaKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("ecdsa.GenerateKey failed [%s]", err)
+	}
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("rsa.GenerateKey failed [%s]", err)
+	}
+	_, ed25519Key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("ed25519.GenerateKey failed [%s]", err)
+	}
+	sm2Key, err := sm2.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("sm2.GenerateKey failed [%s]", err)
+	}
+
+	cases := []struct {
+		name    string
+		key     crypto.PrivateKey
+		sameKey func(t *testing.T, got crypto.Signer)
+	}{
+		{"ecdsa", ecdsaKey, func(t *testing.T, got crypto.Signer) {
+			gotKey, ok := got.(*ecdsa.PrivateKey)
+			if !ok {
+				t.Fatalf("recovered key has type %T, want *ecdsa.PrivateKey", got)
+			}
+			if gotKey.D.Cmp(ecdsaKey.D) != 0 {
+				t.Fatal("recovered private key does not match the original")
+			}
+		}},
+		{"rsa", rsaKey, func(t *testing.T, got crypto.Signer) {
+			gotKey, ok := got.(*rsa.PrivateKey)
+			if !ok {
+				t.Fatalf("recovered key has type %T, want *rsa.PrivateKey", got)
+			}
+			if gotKey.D.Cmp(rsaKey.D) != 0 {
+				t.Fatal("recovered private key does not match the original")
+			}
+		}},
+		{"ed25519", ed25519Key, func(t *testing.T, got crypto.Signer) {
+			gotKey, ok := got.(ed25519.PrivateKey)
+			if !ok {
+				t.Fatalf("recovered key has type %T, want ed25519.PrivateKey", got)
+			}
+			if !gotKey.Equal(ed25519Key) {
+				t.Fatal("recovered private key does not match the original")
+			}
+		}},
+		{"sm2", sm2Key, func(t *testing.T, got crypto.Signer) {
+			gotKey, ok := got.(*sm2.PrivateKey)
+			if !ok {
+				t.Fatalf("recovered key has type %T, want *sm2.PrivateKey", got)
+			}
+			if gotKey.D.Cmp(sm2Key.D) != 0 {
+				t.Fatal("recovered private key does not match the original")
+			}
+		}},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			pemBytes, err := PrivateKeyToPEMAny(c.key, nil)
+			if err != nil {
+				t.Fatalf("PrivateKeyToPEMAny failed [%s]", err)
+			}
+			signer, err := PEMtoPrivateKeyAny(pemBytes, nil)
+			if err != nil {
+				t.Fatalf("PEMtoPrivateKeyAny failed [%s]", err)
+			}
+			c.sameKey(t, signer)
+		})
+	}
+}
+
+func TestPrivateKeyToPEMAnyEncryptedRoundTrip(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("rsa.GenerateKey failed [%s]", err)
+	}
+
+	pemBytes, err := PrivateKeyToPEMAny(rsaKey, []byte("s3cret"))
+	if err != nil {
+		t.Fatalf("PrivateKeyToPEMAny failed [%s]", err)
+	}
+	if _, err := PEMtoPrivateKeyAny(pemBytes, []byte("s3cret")); err != nil {
+		t.Fatalf("PEMtoPrivateKeyAny failed [%s]", err)
+	}
+	if _, err := PEMtoPrivateKeyAny(pemBytes, []byte("wrong")); err == nil {
+		t.Fatal("expected an error decrypting with the wrong password, got nil")
+	}
+}
+
+func TestPEMtoPrivateKeyAnyInvalidPEM(t *testing.T) {
+	if _, err := PEMtoPrivateKeyAny([]byte("not a PEM block"), nil); err == nil {
+		t.Fatal("expected an error for an unparseable PEM, got nil")
+	}
+}
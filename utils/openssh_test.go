@@ -0,0 +1,72 @@
+package utils
+
+import (
+	"crypto/rand"
+	"testing"
+
+	"github.com/hw09234/gm-crypto/sm2"
+)
+
+func TestPrivateKeyToOpenSSHRoundTrip(t *testing.T) {
+	priv, err := sm2.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("sm2.GenerateKey failed [%s]", err)
+	}
+
+	raw, err := PrivateKeyToOpenSSH(priv, "test@example.com", nil)
+	if err != nil {
+		t.Fatalf("PrivateKeyToOpenSSH failed [%s]", err)
+	}
+
+	got, err := OpenSSHToPrivateKey(raw, nil)
+	if err != nil {
+		t.Fatalf("OpenSSHToPrivateKey failed [%s]", err)
+	}
+	if got.D.Cmp(priv.D) != 0 {
+		t.Fatal("recovered private key does not match the original")
+	}
+}
+
+func TestPrivateKeyToOpenSSHEncryptedRoundTrip(t *testing.T) {
+	priv, err := sm2.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("sm2.GenerateKey failed [%s]", err)
+	}
+
+	raw, err := PrivateKeyToOpenSSH(priv, "test@example.com", []byte("s3cret"))
+	if err != nil {
+		t.Fatalf("PrivateKeyToOpenSSH failed [%s]", err)
+	}
+
+	got, err := OpenSSHToPrivateKey(raw, []byte("s3cret"))
+	if err != nil {
+		t.Fatalf("OpenSSHToPrivateKey failed [%s]", err)
+	}
+	if got.D.Cmp(priv.D) != 0 {
+		t.Fatal("recovered private key does not match the original")
+	}
+
+	if _, err := OpenSSHToPrivateKey(raw, []byte("wrong")); err == nil {
+		t.Fatal("expected an error decrypting with the wrong password, got nil")
+	}
+}
+
+func TestAuthorizedKeyRoundTrip(t *testing.T) {
+	priv, err := sm2.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("sm2.GenerateKey failed [%s]", err)
+	}
+
+	line, err := PublicKeyToAuthorizedKey(&priv.PublicKey, "test@example.com")
+	if err != nil {
+		t.Fatalf("PublicKeyToAuthorizedKey failed [%s]", err)
+	}
+
+	got, err := AuthorizedKeyToPublicKey(line)
+	if err != nil {
+		t.Fatalf("AuthorizedKeyToPublicKey failed [%s]", err)
+	}
+	if got.X.Cmp(priv.X) != 0 || got.Y.Cmp(priv.Y) != 0 {
+		t.Fatal("recovered public key does not match the original")
+	}
+}
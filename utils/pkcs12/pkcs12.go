@@ -0,0 +1,411 @@
+// Package pkcs12 encodes and decodes PKCS#12 (.p12/.pfx) files carrying an
+// SM2 private key and its certificate chain, following the profile used by
+// mainstream GM tooling: the SM2 key is stored in a PKCS#8ShroudedKeyBag
+// (PBES2/PBKDF2/AES-256-CBC), certificates are stored in CertBags, the
+// SafeContents holding both is itself PBES2-encrypted, and the outer
+// integrity MAC is HMAC-SM3.
+package pkcs12
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/rand"
+	"encoding/asn1"
+	"errors"
+	"fmt"
+	"hash"
+	"unicode/utf16"
+
+	"github.com/hw09234/gm-crypto/sm2"
+	"github.com/hw09234/gm-crypto/sm3"
+	"github.com/hw09234/gm-crypto/x509"
+
+	"github.com/hw09234/gm-crypto-utils/utils/internal/pbes2"
+)
+
+// DefaultIterations is the PBKDF2/MAC iteration count used by EncodePKCS12;
+// EncodePKCS12 does not take a caller-chosen count.
+const DefaultIterations = 100000
+
+// macSaltLen is the length of the salt used by the PKCS#12 MAC KDF
+// (computeMAC), a separate salt from the one PBES2 generates internally for
+// SafeContents encryption (pbes2.SaltLen); the two happen to share a value.
+const macSaltLen = 16
+
+var (
+	oidDataContentType          = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 7, 1}
+	oidEncryptedDataContentType = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 7, 6}
+	oidCertBag                  = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 12, 10, 1, 3}
+	oidPKCS8ShroudedKeyBag      = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 12, 10, 1, 2}
+	oidCertTypeX509Certificate  = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 22, 1}
+
+	oidSM3 = asn1.ObjectIdentifier{1, 2, 156, 10197, 1, 401}
+)
+
+type pfxPdu struct {
+	Version  int
+	AuthSafe contentInfo
+	MacData  macData `asn1:"optional"`
+}
+
+type contentInfo struct {
+	ContentType asn1.ObjectIdentifier
+	Content     asn1.RawValue `asn1:"explicit,optional,tag:0"`
+}
+
+type encryptedData struct {
+	Version              int
+	EncryptedContentInfo encryptedContentInfo
+}
+
+type encryptedContentInfo struct {
+	ContentType                asn1.ObjectIdentifier
+	ContentEncryptionAlgorithm pbes2.AlgorithmIdentifier
+	EncryptedContent           []byte `asn1:"optional,tag:0"`
+}
+
+type macData struct {
+	Mac        digestInfo
+	MacSalt    []byte
+	Iterations int `asn1:"optional,default:1"`
+}
+
+type digestInfo struct {
+	Algorithm pbes2.AlgorithmIdentifier
+	Digest    []byte
+}
+
+type safeBag struct {
+	ID    asn1.ObjectIdentifier
+	Value asn1.RawValue `asn1:"tag:0,explicit"`
+}
+
+type certBag struct {
+	ID   asn1.ObjectIdentifier
+	Data []byte `asn1:"tag:0,explicit"`
+}
+
+// EncodePKCS12 packages an SM2 private key, its certificate, and an optional
+// chain of issuer certificates into a password-protected PKCS#12 file.
+func EncodePKCS12(priv *sm2.PrivateKey, cert *x509.Certificate, chain []*x509.Certificate, pwd []byte) ([]byte, error) {
+	if priv == nil {
+		return nil, errors.New("invalid private key. It must be different from nil")
+	}
+	if cert == nil {
+		return nil, errors.New("invalid certificate. It must be different from nil")
+	}
+	if len(pwd) == 0 {
+		return nil, errors.New("invalid password. It must be different from nil")
+	}
+
+	keyBagValue, err := marshalShroudedKeyBagValue(priv, pwd)
+	if err != nil {
+		return nil, fmt.Errorf("failed shrouding SM2 key [%s]", err)
+	}
+
+	bags := []safeBag{{
+		ID:    oidPKCS8ShroudedKeyBag,
+		Value: explicitRawValue(keyBagValue),
+	}}
+	for _, c := range append([]*x509.Certificate{cert}, chain...) {
+		bagValue, err := asn1.Marshal(certBag{ID: oidCertTypeX509Certificate, Data: c.Raw})
+		if err != nil {
+			return nil, err
+		}
+		bags = append(bags, safeBag{ID: oidCertBag, Value: explicitRawValue(bagValue)})
+	}
+
+	safeContents, err := asn1.Marshal(bags)
+	if err != nil {
+		return nil, err
+	}
+
+	encryptedContent, algo, err := pbes2.Encrypt(safeContents, pwd, DefaultIterations)
+	if err != nil {
+		return nil, err
+	}
+	encData, err := asn1.Marshal(encryptedData{
+		Version: 0,
+		EncryptedContentInfo: encryptedContentInfo{
+			ContentType:                oidDataContentType,
+			ContentEncryptionAlgorithm: algo,
+			EncryptedContent:           encryptedContent,
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	authSafe, err := asn1.Marshal([]contentInfo{{
+		ContentType: oidEncryptedDataContentType,
+		Content:     explicitRawValue(encData),
+	}})
+	if err != nil {
+		return nil, err
+	}
+
+	macSalt := make([]byte, macSaltLen)
+	if _, err := rand.Read(macSalt); err != nil {
+		return nil, err
+	}
+	mac := computeMAC(authSafe, macSalt, DefaultIterations, pwd)
+
+	// The content of a "data" ContentInfo is an OCTET STRING wrapping the
+	// DER encoding of the AuthenticatedSafe; asn1.Marshal of a []byte value
+	// produces exactly that OCTET STRING TLV.
+	authSafeOctets, err := asn1.Marshal(authSafe)
+	if err != nil {
+		return nil, err
+	}
+
+	pfx := pfxPdu{
+		Version: 3,
+		AuthSafe: contentInfo{
+			ContentType: oidDataContentType,
+			Content:     explicitRawValue(authSafeOctets),
+		},
+		MacData: macData{
+			Mac:        digestInfo{Algorithm: pbes2.AlgorithmIdentifier{Algorithm: oidSM3}, Digest: mac},
+			MacSalt:    macSalt,
+			Iterations: DefaultIterations,
+		},
+	}
+
+	return asn1.Marshal(pfx)
+}
+
+// explicitRawValue wraps der - the complete DER encoding of some ASN.1
+// value - in a context-specific, constructed [0] EXPLICIT tag. Per X.690,
+// explicit tagging is "wrap the full TLV of the underlying type under a new
+// tag", so der is used as-is for Bytes; asn1.RawValue.FullBytes must NOT be
+// used here, since the encoder special-cases FullBytes and writes it
+// verbatim, bypassing the struct field's own "explicit,tag:0" annotation.
+// Symmetrically, a field decoded through this wrapper has its RawValue's
+// Bytes (not FullBytes) set back to der - FullBytes instead holds the
+// wrapper's own [0] EXPLICIT TLV.
+func explicitRawValue(der []byte) asn1.RawValue {
+	return asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 0, IsCompound: true, Bytes: der}
+}
+
+// DecodePKCS12 parses a password-protected PKCS#12 file produced by
+// EncodePKCS12 (or an equivalent GM-profile PFX), returning the SM2 private
+// key, its certificate, and any chain certificates that followed it.
+func DecodePKCS12(data, pwd []byte) (*sm2.PrivateKey, *x509.Certificate, []*x509.Certificate, error) {
+	var pfx pfxPdu
+	if _, err := asn1.Unmarshal(data, &pfx); err != nil {
+		return nil, nil, nil, fmt.Errorf("failed parsing PFX [%s]", err)
+	}
+
+	// Content, after the struct-level [0] EXPLICIT unwrap performed by
+	// asn1.Unmarshal, is a RawValue whose Bytes hold the complete OCTET
+	// STRING TLV (not its payload - see explicitRawValue), so one more
+	// unmarshal is needed to recover the AuthenticatedSafe DER itself.
+	var authSafe []byte
+	if _, err := asn1.Unmarshal(pfx.AuthSafe.Content.Bytes, &authSafe); err != nil {
+		return nil, nil, nil, fmt.Errorf("failed parsing AuthenticatedSafe OCTET STRING [%s]", err)
+	}
+
+	if len(pfx.MacData.MacSalt) != 0 {
+		expected := computeMAC(authSafe, pfx.MacData.MacSalt, pfx.MacData.Iterations, pwd)
+		if !hmac.Equal(expected, pfx.MacData.Mac.Digest) {
+			return nil, nil, nil, errors.New("pkcs12: MAC mismatch - incorrect password or corrupted file")
+		}
+	}
+
+	var contentInfos []contentInfo
+	if _, err := asn1.Unmarshal(authSafe, &contentInfos); err != nil {
+		return nil, nil, nil, fmt.Errorf("failed parsing AuthenticatedSafe [%s]", err)
+	}
+
+	var priv *sm2.PrivateKey
+	var leafCert *x509.Certificate
+	var chain []*x509.Certificate
+
+	for _, ci := range contentInfos {
+		if !ci.ContentType.Equal(oidEncryptedDataContentType) {
+			return nil, nil, nil, fmt.Errorf("unsupported AuthenticatedSafe content type %v", ci.ContentType)
+		}
+		var encData encryptedData
+		if _, err := asn1.Unmarshal(ci.Content.Bytes, &encData); err != nil {
+			return nil, nil, nil, fmt.Errorf("failed parsing EncryptedData [%s]", err)
+		}
+		safeContents, err := pbes2.Decrypt(encData.EncryptedContentInfo.ContentEncryptionAlgorithm, encData.EncryptedContentInfo.EncryptedContent, pwd)
+		if err != nil {
+			return nil, nil, nil, fmt.Errorf("failed decrypting SafeContents [%s]", err)
+		}
+
+		var bags []safeBag
+		if _, err := asn1.Unmarshal(safeContents, &bags); err != nil {
+			return nil, nil, nil, fmt.Errorf("failed parsing SafeContents [%s]", err)
+		}
+
+		for _, bag := range bags {
+			switch {
+			case bag.ID.Equal(oidPKCS8ShroudedKeyBag):
+				priv, err = unmarshalShroudedKeyBagValue(bag.Value.Bytes, pwd)
+				if err != nil {
+					return nil, nil, nil, fmt.Errorf("failed unshrouding SM2 key [%s]", err)
+				}
+			case bag.ID.Equal(oidCertBag):
+				var cb certBag
+				if _, err := asn1.Unmarshal(bag.Value.Bytes, &cb); err != nil {
+					return nil, nil, nil, fmt.Errorf("failed parsing CertBag [%s]", err)
+				}
+				cert, err := x509.ParseCertificate(cb.Data)
+				if err != nil {
+					return nil, nil, nil, fmt.Errorf("failed parsing certificate [%s]", err)
+				}
+				if leafCert == nil {
+					leafCert = cert
+				} else {
+					chain = append(chain, cert)
+				}
+			default:
+				return nil, nil, nil, fmt.Errorf("unsupported SafeBag type %v", bag.ID)
+			}
+		}
+	}
+
+	if priv == nil {
+		return nil, nil, nil, errors.New("pkcs12: no SM2 private key found")
+	}
+	if leafCert == nil {
+		return nil, nil, nil, errors.New("pkcs12: no certificate found")
+	}
+
+	return priv, leafCert, chain, nil
+}
+
+// marshalShroudedKeyBagValue encodes priv as an SM2 ecPrivateKey wrapped in
+// PKCS#8, individually encrypted (PBES2/PBKDF2/AES-256-CBC) into an
+// EncryptedPrivateKeyInfo, and returns its DER encoding. The caller wraps
+// the result in the [0] EXPLICIT tag a PKCS8ShroudedKeyBag's Value requires
+// (see explicitRawValue).
+func marshalShroudedKeyBagValue(priv *sm2.PrivateKey, pwd []byte) ([]byte, error) {
+	pkcs8Bytes, err := pbes2.MarshalSM2PKCS8(priv)
+	if err != nil {
+		return nil, err
+	}
+
+	encrypted, algo, err := pbes2.Encrypt(pkcs8Bytes, pwd, DefaultIterations)
+	if err != nil {
+		return nil, err
+	}
+	return asn1.Marshal(pbes2.EncryptedPrivateKeyInfo{Algo: algo, EncryptedData: encrypted})
+}
+
+// unmarshalShroudedKeyBagValue reverses marshalShroudedKeyBagValue. raw is
+// the SafeBag Value's Bytes - the EncryptedPrivateKeyInfo DER that was
+// wrapped in the [0] EXPLICIT tag, already stripped of that wrapper by
+// asn1.Unmarshal (see explicitRawValue).
+func unmarshalShroudedKeyBagValue(raw []byte, pwd []byte) (*sm2.PrivateKey, error) {
+	var info pbes2.EncryptedPrivateKeyInfo
+	if _, err := asn1.Unmarshal(raw, &info); err != nil {
+		return nil, err
+	}
+	pkcs8Bytes, err := pbes2.Decrypt(info.Algo, info.EncryptedData, pwd)
+	if err != nil {
+		return nil, err
+	}
+	key, err := x509.ParsePKCS8PrivateKey(pkcs8Bytes)
+	if err != nil {
+		return nil, err
+	}
+	sm2Key, ok := key.(*sm2.PrivateKey)
+	if !ok {
+		return nil, errors.New("key type error")
+	}
+	return sm2Key, nil
+}
+
+// macKeyID is the "ID" byte used by the PKCS#12 key-derivation function
+// (RFC 7292 Appendix B) when deriving MAC keying material, as opposed to 1
+// for an encryption key or 2 for an IV.
+const macKeyID = 3
+
+// computeMAC derives a MAC key from pwd via the PKCS#12-specific KDF (RFC
+// 7292 Appendix B, "Algorithm B") and returns HMAC-SM3 over data, matching
+// the integrity check real PKCS#12 readers apply to the AuthenticatedSafe
+// before trusting its contents. This is deliberately not plain PBKDF2: the
+// PKCS#12 KDF stretches salt and password differently (see pkcs12KDF) and
+// every mainstream implementation (OpenSSL, Java keytool) requires it.
+func computeMAC(data, salt []byte, iterations int, pwd []byte) []byte {
+	key := pkcs12KDF(sm3.New, sm3.Size, sm3.BlockSize, salt, bmpString(pwd), iterations, macKeyID, sm3.Size)
+	mac := hmac.New(sm3.New, key)
+	mac.Write(data)
+	return mac.Sum(nil)
+}
+
+// pkcs12KDF implements the key-derivation function of RFC 7292 Appendix B
+// ("Algorithm B"), generalized over the underlying hash function. u and v
+// are the hash's digest size and block size in bytes; id selects which kind
+// of keying material is produced (1 = encryption key, 2 = IV, 3 = MAC key);
+// size is the number of output bytes requested.
+func pkcs12KDF(hashFn func() hash.Hash, u, v int, salt, password []byte, iterations int, id byte, size int) []byte {
+	D := bytes.Repeat([]byte{id}, v)
+	S := fillWithRepeats(salt, v)
+	P := fillWithRepeats(password, v)
+	I := append(append([]byte{}, S...), P...)
+
+	numBlocks := (size + u - 1) / u
+	A := make([]byte, 0, numBlocks*u)
+
+	for i := 0; i < numBlocks; i++ {
+		h := hashFn()
+		h.Write(D)
+		h.Write(I)
+		Ai := h.Sum(nil)
+		for j := 1; j < iterations; j++ {
+			h = hashFn()
+			h.Write(Ai)
+			Ai = h.Sum(nil)
+		}
+		A = append(A, Ai...)
+
+		if i < numBlocks-1 {
+			B := fillWithRepeats(Ai, v)
+			for j := 0; j < len(I); j += v {
+				addOne(I[j:j+v], B)
+			}
+		}
+	}
+	return A[:size]
+}
+
+// fillWithRepeats returns pattern repeated end-to-end until it fills a
+// multiple of v bytes, per the "diversify" and "OCTET-STRING(S)/(P)"
+// constructions of RFC 7292 Appendix B.1. It returns v zero bytes if
+// pattern is empty, matching an empty salt or password.
+func fillWithRepeats(pattern []byte, v int) []byte {
+	if len(pattern) == 0 {
+		return make([]byte, v)
+	}
+	out := make([]byte, v*((len(pattern)+v-1)/v))
+	for i := range out {
+		out[i] = pattern[i%len(pattern)]
+	}
+	return out
+}
+
+// addOne adds B + 1, B being a big-endian unsigned integer, to block (of the
+// same length) in place modulo 2^(8*len(block)), per RFC 7292 Appendix B.2,
+// step 6C.
+func addOne(block, B []byte) {
+	carry := 1
+	for i := len(block) - 1; i >= 0; i-- {
+		sum := int(block[i]) + int(B[i]) + carry
+		block[i] = byte(sum)
+		carry = sum >> 8
+	}
+}
+
+// bmpString converts a password to the BMPString (UTF-16BE, NUL-terminated)
+// form RFC 7292 requires as input to the PKCS#12 KDF.
+func bmpString(password []byte) []byte {
+	units := utf16.Encode([]rune(string(password)))
+	out := make([]byte, 0, len(units)*2+2)
+	for _, u := range units {
+		out = append(out, byte(u>>8), byte(u))
+	}
+	return append(out, 0, 0)
+}
@@ -0,0 +1,75 @@
+package pkcs12
+
+import (
+	"crypto/rand"
+	"crypto/x509/pkix"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/hw09234/gm-crypto/sm2"
+	"github.com/hw09234/gm-crypto/x509"
+)
+
+func selfSignedCert(t *testing.T, priv *sm2.PrivateKey) *x509.Certificate {
+	t.Helper()
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "pkcs12 test"},
+		NotBefore:    time.Unix(0, 0),
+		NotAfter:     time.Unix(0, 0).AddDate(10, 0, 0),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &priv.PublicKey, priv)
+	if err != nil {
+		t.Fatalf("CreateCertificate failed [%s]", err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatalf("ParseCertificate failed [%s]", err)
+	}
+	return cert
+}
+
+func TestEncodePKCS12RoundTrip(t *testing.T) {
+	priv, err := sm2.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("sm2.GenerateKey failed [%s]", err)
+	}
+	cert := selfSignedCert(t, priv)
+
+	pfx, err := EncodePKCS12(priv, cert, nil, []byte("s3cret"))
+	if err != nil {
+		t.Fatalf("EncodePKCS12 failed [%s]", err)
+	}
+
+	gotPriv, gotCert, gotChain, err := DecodePKCS12(pfx, []byte("s3cret"))
+	if err != nil {
+		t.Fatalf("DecodePKCS12 failed [%s]", err)
+	}
+	if gotPriv.D.Cmp(priv.D) != 0 {
+		t.Fatal("recovered private key does not match the original")
+	}
+	if string(gotCert.Raw) != string(cert.Raw) {
+		t.Fatal("recovered certificate does not match the original")
+	}
+	if len(gotChain) != 0 {
+		t.Fatalf("expected no chain certificates, got %d", len(gotChain))
+	}
+}
+
+func TestDecodePKCS12WrongPassword(t *testing.T) {
+	priv, err := sm2.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("sm2.GenerateKey failed [%s]", err)
+	}
+	cert := selfSignedCert(t, priv)
+
+	pfx, err := EncodePKCS12(priv, cert, nil, []byte("s3cret"))
+	if err != nil {
+		t.Fatalf("EncodePKCS12 failed [%s]", err)
+	}
+
+	if _, _, _, err := DecodePKCS12(pfx, []byte("wrong")); err == nil {
+		t.Fatal("expected a MAC mismatch error decrypting with the wrong password, got nil")
+	}
+}
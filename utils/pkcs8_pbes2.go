@@ -0,0 +1,101 @@
+package utils
+
+import (
+	"encoding/asn1"
+	"encoding/pem"
+	"errors"
+	"fmt"
+
+	"github.com/hw09234/gm-crypto/sm2"
+
+	"github.com/hw09234/gm-crypto-utils/utils/internal/pbes2"
+)
+
+// DefaultPBKDF2Iterations is the PBKDF2 iteration count used by
+// PrivateKeyToEncryptedPKCS8PEM when the caller does not request a
+// specific one. It is chosen to stay above currently recommended minimums.
+const DefaultPBKDF2Iterations = 100000
+
+// PrivateKeyToEncryptedPKCS8PEM converts an SM2 private key to a
+// password-protected PEM block holding a standards-compliant
+// EncryptedPrivateKeyInfo (RFC 5958): PBES2 with PBKDF2 (HMAC-SHA256 PRF)
+// deriving an AES-256-CBC key that wraps the PKCS#8 encoding of the key.
+// Unlike PrivateKeyToEncryptedPEM, the result does not rely on the legacy
+// OpenSSL "DEK-Info" PEM header and is interoperable with standard PKCS#8
+// tooling.
+func PrivateKeyToEncryptedPKCS8PEM(priKey *sm2.PrivateKey, pwd []byte) ([]byte, error) {
+	return PrivateKeyToEncryptedPKCS8PEMWithIterations(priKey, pwd, DefaultPBKDF2Iterations)
+}
+
+// PrivateKeyToEncryptedPKCS8PEMWithIterations is like
+// PrivateKeyToEncryptedPKCS8PEM but allows the PBKDF2 iteration count to be
+// configured explicitly.
+func PrivateKeyToEncryptedPKCS8PEMWithIterations(priKey *sm2.PrivateKey, pwd []byte, iterations int) ([]byte, error) {
+	if priKey == nil {
+		return nil, errors.New("invalid private key. It must be different from nil")
+	}
+	if len(pwd) == 0 {
+		return nil, errors.New("invalid password. It must be different from nil")
+	}
+	if iterations <= 0 {
+		iterations = DefaultPBKDF2Iterations
+	}
+
+	pkcs8Bytes, err := marshalSM2PKCS8(priKey)
+	if err != nil {
+		return nil, err
+	}
+
+	return encryptPKCS8DER(pkcs8Bytes, pwd, iterations)
+}
+
+// encryptPKCS8DER wraps an unencrypted PKCS#8 DER encoding in a
+// PBES2/PBKDF2/AES-256-CBC EncryptedPrivateKeyInfo and returns it as an
+// "ENCRYPTED PRIVATE KEY" PEM block.
+func encryptPKCS8DER(pkcs8Bytes []byte, pwd []byte, iterations int) ([]byte, error) {
+	if iterations <= 0 {
+		iterations = DefaultPBKDF2Iterations
+	}
+
+	encrypted, algo, err := pbes2.Encrypt(pkcs8Bytes, pwd, iterations)
+	if err != nil {
+		return nil, err
+	}
+
+	der, err := asn1.Marshal(pbes2.EncryptedPrivateKeyInfo{
+		Algo:          algo,
+		EncryptedData: encrypted,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return pem.EncodeToMemory(&pem.Block{
+		Type:  "ENCRYPTED PRIVATE KEY",
+		Bytes: der,
+	}), nil
+}
+
+// marshalSM2PKCS8 marshals an SM2 private key to its unencrypted PKCS#8 DER
+// encoding. It has the same PKCS#8 shape as PrivateKeyToPEM's output, except
+// that the inner ecPrivateKey also carries the SM2 curve OID, which
+// PrivateKeyToPEM omits.
+func marshalSM2PKCS8(priKey *sm2.PrivateKey) ([]byte, error) {
+	return pbes2.MarshalSM2PKCS8(priKey)
+}
+
+// parseEncryptedPKCS8PEMBlock decrypts a PEM block holding an
+// EncryptedPrivateKeyInfo (PBES2/PBKDF2/AES-256-CBC) and returns the inner
+// PKCS#8 DER bytes.
+func parseEncryptedPKCS8PEMBlock(block *pem.Block, pwd []byte) ([]byte, error) {
+	if len(pwd) == 0 {
+		return nil, errors.New("encrypted key. Need a password")
+	}
+
+	var info pbes2.EncryptedPrivateKeyInfo
+	if _, err := asn1.Unmarshal(block.Bytes, &info); err != nil {
+		return nil, fmt.Errorf("failed parsing EncryptedPrivateKeyInfo [%s]", err)
+	}
+
+	return pbes2.Decrypt(info.Algo, info.EncryptedData, pwd)
+}
@@ -0,0 +1,44 @@
+package utils
+
+import (
+	"crypto/rand"
+	"testing"
+
+	"github.com/hw09234/gm-crypto/sm2"
+)
+
+func TestPrivateKeyToEncryptedPKCS8PEMRoundTrip(t *testing.T) {
+	priv, err := sm2.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("GenerateKey failed [%s]", err)
+	}
+
+	pem, err := PrivateKeyToEncryptedPKCS8PEM(priv, []byte("correct horse battery staple"))
+	if err != nil {
+		t.Fatalf("PrivateKeyToEncryptedPKCS8PEM failed [%s]", err)
+	}
+
+	got, err := PEMtoPrivateKey(pem, []byte("correct horse battery staple"))
+	if err != nil {
+		t.Fatalf("PEMtoPrivateKey failed [%s]", err)
+	}
+	if got.D.Cmp(priv.D) != 0 {
+		t.Fatal("recovered private key does not match the original")
+	}
+}
+
+func TestPrivateKeyToEncryptedPKCS8PEMWrongPassword(t *testing.T) {
+	priv, err := sm2.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("GenerateKey failed [%s]", err)
+	}
+
+	pem, err := PrivateKeyToEncryptedPKCS8PEM(priv, []byte("correct horse battery staple"))
+	if err != nil {
+		t.Fatalf("PrivateKeyToEncryptedPKCS8PEM failed [%s]", err)
+	}
+
+	if _, err := PEMtoPrivateKey(pem, []byte("wrong password")); err == nil {
+		t.Fatal("expected an error decrypting with the wrong password, got nil")
+	}
+}